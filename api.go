@@ -0,0 +1,275 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Console WebSocket tuning, mirrors the gorilla/websocket "command" example.
+const (
+	consoleWriteWait      = 10 * time.Second           // Time allowed to write a message to the peer
+	consolePongWait       = 60 * time.Second           // Time allowed to read the next pong message from the peer
+	consolePingPeriod     = (consolePongWait * 9) / 10 // Send pings to peer with this period (must be less than consolePongWait)
+	consoleMaxMessageSize = 8192                       // Maximum message size allowed from peer, in bytes
+)
+
+// instanceSummary is the JSON shape returned by the /api/instances endpoints.
+type instanceSummary struct {
+	ID      string   `json:"id"`
+	Name    string   `json:"name"`
+	Port    int      `json:"port"`
+	ModPack string   `json:"mod_pack"`
+	Running bool     `json:"running"`
+	Players []string `json:"players"`
+}
+
+func summarize(inst *Instance) instanceSummary {
+	return instanceSummary{
+		ID:      inst.ID,
+		Name:    inst.Name,
+		Port:    inst.Port,
+		ModPack: inst.ModPack,
+		Running: inst.isRunning(),
+		Players: inst.players.list(),
+	}
+}
+
+// instancesHandler serves /api/instances and every /api/instances/{id}/...
+// sub-route. The repo has no path-parameter router, so routes below the
+// list endpoint are dispatched by hand.
+func instancesHandler(w http.ResponseWriter, r *http.Request) {
+	rest := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/instances"), "/")
+
+	if rest == "" {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		listInstancesHandler(w, r)
+		return
+	}
+
+	parts := strings.SplitN(rest, "/", 2)
+	id := parts[0]
+	inst, ok := manager.Get(id)
+	if !ok {
+		http.Error(w, "Unknown instance", http.StatusNotFound)
+		return
+	}
+
+	if len(parts) == 1 {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		writeJSON(w, summarize(inst))
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// config edits are admin-only; every other sub-route only needs operator.
+	minRole := RoleOperator
+	if parts[1] == "config" {
+		minRole = RoleAdmin
+	}
+	if role, ok := roleFromRequest(r); !ok || role < minRole {
+		http.Error(w, "Insufficient permissions", http.StatusForbidden)
+		return
+	}
+
+	switch parts[1] {
+	case "start":
+		if err := manager.Start(id); err != nil {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+	case "stop":
+		if err := manager.Stop(id); err != nil {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+	case "restart":
+		if err := manager.Restart(id); err != nil {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+	case "command":
+		var body struct {
+			Command string `json:"command"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Command == "" {
+			http.Error(w, "Missing \"command\" in request body", http.StatusBadRequest)
+			return
+		}
+		if err := manager.Command(id, body.Command); err != nil {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+	case "config":
+		var patch instanceConfigPatch
+		if err := json.NewDecoder(r.Body).Decode(&patch); err != nil {
+			http.Error(w, "Invalid config patch", http.StatusBadRequest)
+			return
+		}
+		if _, err := manager.UpdateConfig(id, patch); err != nil {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+	default:
+		http.NotFound(w, r)
+		return
+	}
+
+	writeJSON(w, summarize(inst))
+}
+
+// listInstancesHandler handles GET /api/instances.
+func listInstancesHandler(w http.ResponseWriter, r *http.Request) {
+	instances := manager.List()
+	summaries := make([]instanceSummary, 0, len(instances))
+	for _, inst := range instances {
+		summaries = append(summaries, summarize(inst))
+	}
+	writeJSON(w, summaries)
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Println("error encoding JSON response:", err)
+	}
+}
+
+// instanceFromQuery resolves the ?instance= query parameter against the
+// manager, writing an HTTP error and returning ok=false if it's missing or
+// unknown.
+func instanceFromQuery(w http.ResponseWriter, r *http.Request) (*Instance, bool) {
+	id := r.URL.Query().Get("instance")
+	if id == "" {
+		http.Error(w, "Missing \"instance\" query parameter", http.StatusBadRequest)
+		return nil, false
+	}
+	inst, ok := manager.Get(id)
+	if !ok {
+		http.Error(w, "Unknown instance", http.StatusNotFound)
+		return nil, false
+	}
+	return inst, true
+}
+
+// wsHandler subscribes a browser to one instance's typed event bus and
+// streams every published Event to it until the connection drops. Any
+// authenticated role may subscribe.
+func wsHandler(w http.ResponseWriter, r *http.Request) {
+	if _, ok := authenticateWS(w, r, RoleViewer); !ok {
+		return
+	}
+
+	inst, ok := instanceFromQuery(w, r)
+	if !ok {
+		return
+	}
+
+	conn, err := upgrader.Upgrade(w, r, wsSubprotocolHeader(r))
+	if err != nil {
+		log.Println("WS upgrade failed:", err)
+		return
+	}
+	defer func() {
+		if err := conn.Close(); err != nil {
+			log.Printf("Error closing WebSocket connection: %v", err)
+		}
+	}()
+
+	ch := inst.bus.subscribe(conn)
+	websocketClients.WithLabelValues(inst.ID).Inc()
+	defer websocketClients.WithLabelValues(inst.ID).Dec()
+	defer inst.bus.unsubscribe(conn)
+	go writePump(conn, ch)
+
+	// Send the current status immediately so new subscribers don't have to
+	// wait for the next heartbeat to know where things stand.
+	inst.publishStatus()
+
+	for {
+		if _, _, err := conn.ReadMessage(); err != nil {
+			break // client disconnected or connection error
+		}
+	}
+}
+
+// consoleHandler upgrades the HTTP connection to a WebSocket that streams
+// one instance's console in real time and accepts operator commands. Only
+// operators and admins may connect; viewers may only subscribe to /ws.
+func consoleHandler(w http.ResponseWriter, r *http.Request) {
+	if _, ok := authenticateWS(w, r, RoleOperator); !ok {
+		return
+	}
+
+	inst, ok := instanceFromQuery(w, r)
+	if !ok {
+		return
+	}
+
+	conn, err := upgrader.Upgrade(w, r, wsSubprotocolHeader(r))
+	if err != nil {
+		log.Println("console WS upgrade failed:", err)
+		return
+	}
+
+	inst.addConsoleClient(conn)
+	websocketClients.WithLabelValues(inst.ID).Inc()
+	defer websocketClients.WithLabelValues(inst.ID).Dec()
+	defer inst.removeConsoleClient(conn)
+
+	conn.SetReadLimit(consoleMaxMessageSize)
+	_ = conn.SetReadDeadline(time.Now().Add(consolePongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(consolePongWait))
+	})
+
+	// close-grace: stop the keepalive pinger as soon as the reader exits so
+	// a browser disconnect can't leak a goroutine waiting on a dead socket.
+	done := make(chan struct{})
+	go consolePingLoop(conn, done)
+	defer close(done)
+
+	for {
+		_, msg, err := conn.ReadMessage()
+		if err != nil {
+			break // client disconnected or connection error, stop forwarding
+		}
+		if err := inst.command(string(msg)); err != nil {
+			log.Println("console stdin write failed:", err)
+			break
+		}
+	}
+}
+
+// consolePingLoop keeps a console WebSocket connection alive and tears it
+// down if the peer stops responding, without touching the child process.
+func consolePingLoop(conn *websocket.Conn, done <-chan struct{}) {
+	ticker := time.NewTicker(consolePingPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			_ = conn.SetWriteDeadline(time.Now().Add(consoleWriteWait))
+			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		case <-done:
+			return
+		}
+	}
+}
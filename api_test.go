@@ -0,0 +1,105 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func tokenFor(t *testing.T, role Role) string {
+	t.Helper()
+	jwtSecret = []byte("test-secret")
+	tok, err := signToken("tester", role, "", time.Minute)
+	if err != nil {
+		t.Fatalf("signToken: %v", err)
+	}
+	return tok
+}
+
+func TestInstancesHandlerListAndGet(t *testing.T) {
+	manager = NewServerManager([]InstanceConfig{{ID: "test", Name: "Test"}}, "")
+
+	req := httptest.NewRequest(http.MethodGet, "/api/instances", nil)
+	w := httptest.NewRecorder()
+	instancesHandler(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("list: got status %d", w.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/instances/test", nil)
+	w = httptest.NewRecorder()
+	instancesHandler(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("get: got status %d", w.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/instances/missing", nil)
+	w = httptest.NewRecorder()
+	instancesHandler(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("get unknown: got status %d, want 404", w.Code)
+	}
+}
+
+func TestInstancesHandlerStartRequiresOperator(t *testing.T) {
+	manager = NewServerManager([]InstanceConfig{{ID: "test", ExpectScript: "sleep 2"}}, "")
+
+	req := httptest.NewRequest(http.MethodPost, "/api/instances/test/start", nil)
+	w := httptest.NewRecorder()
+	instancesHandler(w, req)
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("start without token: got status %d, want 403", w.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/api/instances/test/start", nil)
+	req.Header.Set("Authorization", "Bearer "+tokenFor(t, RoleOperator))
+	w = httptest.NewRecorder()
+	instancesHandler(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("start with operator token: got status %d, body %s", w.Code, w.Body)
+	}
+
+	inst, _ := manager.Get("test")
+	inst.kill()
+}
+
+func TestInstancesHandlerConfigRequiresAdmin(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "instances.json")
+	manager = NewServerManager([]InstanceConfig{{ID: "test", Name: "old"}}, path)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/instances/test/config", strings.NewReader(`{"name":"new"}`))
+	req.Header.Set("Authorization", "Bearer "+tokenFor(t, RoleOperator))
+	w := httptest.NewRecorder()
+	instancesHandler(w, req)
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("config with operator token: got status %d, want 403", w.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/api/instances/test/config", strings.NewReader(`{"name":"new"}`))
+	req.Header.Set("Authorization", "Bearer "+tokenFor(t, RoleAdmin))
+	w = httptest.NewRecorder()
+	instancesHandler(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("config with admin token: got status %d, body %s", w.Code, w.Body)
+	}
+
+	inst, _ := manager.Get("test")
+	if inst.Name != "new" {
+		t.Errorf("Name = %q, want %q", inst.Name, "new")
+	}
+}
+
+func TestInstancesHandlerUnknownSubroute(t *testing.T) {
+	manager = NewServerManager([]InstanceConfig{{ID: "test"}}, "")
+
+	req := httptest.NewRequest(http.MethodPost, "/api/instances/test/bogus", nil)
+	req.Header.Set("Authorization", "Bearer "+tokenFor(t, RoleOperator))
+	w := httptest.NewRecorder()
+	instancesHandler(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("got status %d, want 404", w.Code)
+	}
+}
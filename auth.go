@@ -0,0 +1,427 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Role is a control-endpoint permission level. Roles are ordered: a higher
+// role can do everything a lower one can.
+type Role int
+
+const (
+	RoleViewer Role = iota
+	RoleOperator
+	RoleAdmin
+)
+
+func (r Role) String() string {
+	switch r {
+	case RoleViewer:
+		return "viewer"
+	case RoleOperator:
+		return "operator"
+	case RoleAdmin:
+		return "admin"
+	default:
+		return "unknown"
+	}
+}
+
+func parseRole(s string) (Role, bool) {
+	switch s {
+	case "viewer":
+		return RoleViewer, true
+	case "operator":
+		return RoleOperator, true
+	case "admin":
+		return RoleAdmin, true
+	default:
+		return 0, false
+	}
+}
+
+const (
+	accessTokenTTL  = 15 * time.Minute
+	refreshTokenTTL = 7 * 24 * time.Hour
+)
+
+// claims is the JWT payload carried by both access and refresh tokens. CSRF
+// is a per-login nonce the client must echo back on mutating requests via
+// the X-CSRF-Token header (see requireRole).
+type claims struct {
+	Role string `json:"role"`
+	CSRF string `json:"csrf,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// jwtSecret signs every token this process issues. It's set once in main
+// from the TMOD_JWT_SECRET environment variable.
+var jwtSecret []byte
+
+func signToken(username string, role Role, csrf string, ttl time.Duration) (string, error) {
+	c := claims{
+		Role: role.String(),
+		CSRF: csrf,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   username,
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, c).SignedString(jwtSecret)
+}
+
+func parseToken(tokenString string) (*claims, error) {
+	var c claims
+	token, err := jwt.ParseWithClaims(tokenString, &c, func(t *jwt.Token) (interface{}, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method %v", t.Header["alg"])
+		}
+		return jwtSecret, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !token.Valid {
+		return nil, errors.New("invalid token")
+	}
+	return &c, nil
+}
+
+func randomToken() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		// crypto/rand only fails if the OS source is broken; there is
+		// nothing useful to do but fall back to a fixed-width zero token
+		// rather than panic an HTTP handler.
+		return strings.Repeat("0", hex.EncodedLen(len(b)))
+	}
+	return hex.EncodeToString(b)
+}
+
+// user is one entry in the bcrypt-hashed user file.
+type user struct {
+	Username     string `json:"username"`
+	PasswordHash string `json:"password_hash"`
+	Role         string `json:"role"`
+}
+
+type usersFile struct {
+	Users []user `json:"users"`
+}
+
+// userStore looks users up by name for authentication.
+type userStore struct {
+	byName map[string]user
+}
+
+// loadUserStore reads the bcrypt-hashed user file at path.
+func loadUserStore(path string) (*userStore, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading user file %q: %w", path, err)
+	}
+	var parsed usersFile
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		return nil, fmt.Errorf("parsing user file %q: %w", path, err)
+	}
+
+	store := &userStore{byName: make(map[string]user, len(parsed.Users))}
+	for _, u := range parsed.Users {
+		if _, ok := parseRole(u.Role); !ok {
+			return nil, fmt.Errorf("user %q has unknown role %q", u.Username, u.Role)
+		}
+		store.byName[u.Username] = u
+	}
+	return store, nil
+}
+
+// authenticate checks a username/password pair against the store and
+// returns the user's role on success.
+func (s *userStore) authenticate(username, password string) (Role, error) {
+	u, ok := s.byName[username]
+	if !ok {
+		// Still run bcrypt against a dummy hash so a missing username takes
+		// the same time as a wrong password and doesn't leak which is which.
+		_ = bcrypt.CompareHashAndPassword([]byte("$2a$10$"+strings.Repeat("x", 53)), []byte(password))
+		return 0, errors.New("invalid credentials")
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(password)); err != nil {
+		return 0, errors.New("invalid credentials")
+	}
+	role, _ := parseRole(u.Role)
+	return role, nil
+}
+
+// loginLimiter locks out an identity (username, here) after too many failed
+// attempts in a row, resetting once a login succeeds or the window lapses.
+type loginLimiter struct {
+	mu     sync.Mutex
+	state  map[string]*loginAttempts
+	max    int
+	window time.Duration
+}
+
+type loginAttempts struct {
+	failures int
+	lockedAt time.Time
+}
+
+func newLoginLimiter(max int, window time.Duration) *loginLimiter {
+	return &loginLimiter{state: make(map[string]*loginAttempts), max: max, window: window}
+}
+
+// allow reports whether username is currently allowed to attempt a login.
+func (l *loginLimiter) allow(username string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	a, ok := l.state[username]
+	if !ok {
+		return true
+	}
+	if a.failures < l.max {
+		return true
+	}
+	if time.Since(a.lockedAt) > l.window {
+		delete(l.state, username)
+		return true
+	}
+	return false
+}
+
+func (l *loginLimiter) recordFailure(username string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	a, ok := l.state[username]
+	if !ok {
+		a = &loginAttempts{}
+		l.state[username] = a
+	}
+	a.failures++
+	if a.failures >= l.max {
+		a.lockedAt = time.Now()
+	}
+}
+
+func (l *loginLimiter) recordSuccess(username string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.state, username)
+}
+
+var failedLogins = newLoginLimiter(5, 5*time.Minute)
+
+type loginRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+type tokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	CSRFToken    string `json:"csrf_token"`
+	Role         string `json:"role"`
+	ExpiresIn    int    `json:"expires_in"`
+}
+
+// loginHandler authenticates a username/password pair against the user
+// file and issues an access token, a refresh token and a CSRF token.
+func loginHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req loginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Username == "" {
+		http.Error(w, "Invalid login request", http.StatusBadRequest)
+		return
+	}
+
+	if !failedLogins.allow(req.Username) {
+		http.Error(w, "Too many failed login attempts, try again later", http.StatusTooManyRequests)
+		return
+	}
+
+	role, err := users.authenticate(req.Username, req.Password)
+	if err != nil {
+		failedLogins.recordFailure(req.Username)
+		http.Error(w, "Invalid credentials", http.StatusUnauthorized)
+		return
+	}
+	failedLogins.recordSuccess(req.Username)
+
+	issueTokens(w, req.Username, role)
+}
+
+// refreshHandler exchanges a valid refresh token for a new access token.
+func refreshHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.RefreshToken == "" {
+		http.Error(w, "Invalid refresh request", http.StatusBadRequest)
+		return
+	}
+
+	c, err := parseToken(req.RefreshToken)
+	if err != nil {
+		http.Error(w, "Invalid or expired refresh token", http.StatusUnauthorized)
+		return
+	}
+	role, ok := parseRole(c.Role)
+	if !ok {
+		http.Error(w, "Invalid token role", http.StatusUnauthorized)
+		return
+	}
+
+	issueTokens(w, c.Subject, role)
+}
+
+// issueTokens mints a fresh access/refresh/CSRF token triple for username
+// and writes it as the JSON response body.
+func issueTokens(w http.ResponseWriter, username string, role Role) {
+	csrf := randomToken()
+
+	access, err := signToken(username, role, csrf, accessTokenTTL)
+	if err != nil {
+		http.Error(w, "Failed to issue token", http.StatusInternalServerError)
+		return
+	}
+	refresh, err := signToken(username, role, csrf, refreshTokenTTL)
+	if err != nil {
+		http.Error(w, "Failed to issue token", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, tokenResponse{
+		AccessToken:  access,
+		RefreshToken: refresh,
+		CSRFToken:    csrf,
+		Role:         role.String(),
+		ExpiresIn:    int(accessTokenTTL.Seconds()),
+	})
+}
+
+// bearerToken extracts the token from an "Authorization: Bearer <token>"
+// header.
+func bearerToken(r *http.Request) string {
+	h := r.Header.Get("Authorization")
+	const prefix = "Bearer "
+	if !strings.HasPrefix(h, prefix) {
+		return ""
+	}
+	return strings.TrimPrefix(h, prefix)
+}
+
+// requireRole wraps an http.HandlerFunc so it only runs for requests
+// carrying a valid access token whose role is at least min. Mutating
+// requests (anything but GET/HEAD) must also echo the token's CSRF nonce
+// in the X-CSRF-Token header.
+func requireRole(min Role, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := bearerToken(r)
+		if token == "" {
+			http.Error(w, "Missing bearer token", http.StatusUnauthorized)
+			return
+		}
+		c, err := parseToken(token)
+		if err != nil {
+			http.Error(w, "Invalid or expired token", http.StatusUnauthorized)
+			return
+		}
+		role, ok := parseRole(c.Role)
+		if !ok || role < min {
+			http.Error(w, "Insufficient permissions", http.StatusForbidden)
+			return
+		}
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			if r.Header.Get("X-CSRF-Token") != c.CSRF {
+				http.Error(w, "Missing or invalid CSRF token", http.StatusForbidden)
+				return
+			}
+		}
+		next(w, r)
+	}
+}
+
+// roleFromRequest extracts and validates the bearer token's role without
+// writing an HTTP error, for handlers that need a finer-grained check than
+// the route's baseline requireRole wrapper (e.g. GET vs. POST on the same
+// path).
+func roleFromRequest(r *http.Request) (Role, bool) {
+	token := bearerToken(r)
+	if token == "" {
+		return 0, false
+	}
+	c, err := parseToken(token)
+	if err != nil {
+		return 0, false
+	}
+	return parseRole(c.Role)
+}
+
+// wsToken extracts an access token from a WebSocket upgrade request, either
+// from the ?token= query parameter or the Sec-WebSocket-Protocol header
+// (browsers can't set Authorization headers on WebSocket upgrades).
+func wsToken(r *http.Request) string {
+	if t := r.URL.Query().Get("token"); t != "" {
+		return t
+	}
+	return r.Header.Get("Sec-WebSocket-Protocol")
+}
+
+// wsSubprotocolHeader builds the responseHeader to pass to upgrader.Upgrade
+// so a token offered via Sec-WebSocket-Protocol (the browser
+// `new WebSocket(url, [token])` path, since browsers can't set Authorization
+// headers on WebSocket upgrades) gets echoed back per RFC 6455 — without it,
+// gorilla's subprotocol negotiation never answers and the handshake fails
+// client-side even though the token itself checked out.
+func wsSubprotocolHeader(r *http.Request) http.Header {
+	proto := r.Header.Get("Sec-WebSocket-Protocol")
+	if proto == "" {
+		return nil
+	}
+	return http.Header{"Sec-WebSocket-Protocol": []string{proto}}
+}
+
+// authenticateWS validates the token on a WebSocket upgrade request and
+// returns its role, or ok=false after writing an error response.
+func authenticateWS(w http.ResponseWriter, r *http.Request, min Role) (Role, bool) {
+	token := wsToken(r)
+	if token == "" {
+		http.Error(w, "Missing auth token", http.StatusUnauthorized)
+		return 0, false
+	}
+	c, err := parseToken(token)
+	if err != nil {
+		http.Error(w, "Invalid or expired token", http.StatusUnauthorized)
+		return 0, false
+	}
+	role, ok := parseRole(c.Role)
+	if !ok || role < min {
+		http.Error(w, "Insufficient permissions", http.StatusForbidden)
+		return 0, false
+	}
+	return role, true
+}
+
+// users is the process-wide user store, loaded once in main.
+var users *userStore
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSignAndParseToken(t *testing.T) {
+	jwtSecret = []byte("test-secret")
+
+	token, err := signToken("alice", RoleOperator, "nonce", time.Minute)
+	if err != nil {
+		t.Fatalf("signToken: %v", err)
+	}
+
+	c, err := parseToken(token)
+	if err != nil {
+		t.Fatalf("parseToken: %v", err)
+	}
+	if c.Subject != "alice" || c.Role != "operator" || c.CSRF != "nonce" {
+		t.Fatalf("got %+v", c)
+	}
+}
+
+func TestParseTokenRejectsExpired(t *testing.T) {
+	jwtSecret = []byte("test-secret")
+
+	token, err := signToken("alice", RoleViewer, "", -time.Minute)
+	if err != nil {
+		t.Fatalf("signToken: %v", err)
+	}
+	if _, err := parseToken(token); err == nil {
+		t.Fatal("expected expired token to be rejected")
+	}
+}
+
+func TestRoleOrdering(t *testing.T) {
+	if !(RoleViewer < RoleOperator && RoleOperator < RoleAdmin) {
+		t.Fatal("expected RoleViewer < RoleOperator < RoleAdmin")
+	}
+}
+
+func TestLoginLimiterLocksOutAfterMaxFailures(t *testing.T) {
+	l := newLoginLimiter(3, time.Minute)
+
+	for i := 0; i < 3; i++ {
+		if !l.allow("bob") {
+			t.Fatalf("expected attempt %d to be allowed", i)
+		}
+		l.recordFailure("bob")
+	}
+
+	if l.allow("bob") {
+		t.Fatal("expected bob to be locked out after 3 failures")
+	}
+
+	l.recordSuccess("bob")
+	if !l.allow("bob") {
+		t.Fatal("expected a successful login to clear the lockout")
+	}
+}
@@ -0,0 +1,376 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Backup tuning defaults, used when an InstanceConfig leaves the
+// corresponding field at zero.
+const (
+	defaultBackupKeepDaily  = 7
+	defaultBackupKeepWeekly = 4
+	saveConfirmTimeout      = 30 * time.Second
+	saveConfirmLine         = "World saved"
+)
+
+// backupFile describes one archive on disk, as listed from an instance's
+// backup directory.
+type backupFile struct {
+	path    string
+	name    string
+	modTime time.Time
+	size    int64
+}
+
+// backupDir returns the directory this instance's backup archives are
+// written to, defaulting to backups/<id> if unconfigured.
+func (inst *Instance) backupDir() string {
+	if inst.BackupDir != "" {
+		return inst.BackupDir
+	}
+	return filepath.Join("backups", inst.ID)
+}
+
+func (inst *Instance) keepDaily() int {
+	if inst.BackupKeepDaily > 0 {
+		return inst.BackupKeepDaily
+	}
+	return defaultBackupKeepDaily
+}
+
+func (inst *Instance) keepWeekly() int {
+	if inst.BackupKeepWeekly > 0 {
+		return inst.BackupKeepWeekly
+	}
+	return defaultBackupKeepWeekly
+}
+
+// worldFiles returns the world files this instance backs up: the .wld
+// itself and its paired .twld (tile entity data), alongside WorldPath.
+func (inst *Instance) worldFiles() []string {
+	if inst.WorldPath == "" {
+		return nil
+	}
+	ext := filepath.Ext(inst.WorldPath)
+	base := strings.TrimSuffix(inst.WorldPath, ext)
+	return []string{inst.WorldPath, base + ".twld"}
+}
+
+// runBackup saves the instance's world (if it's running), archives the
+// world and player files into a timestamped tar.gz, rotates old backups
+// away, and publishes backup_started/backup_completed/backup_failed
+// events throughout.
+func (inst *Instance) runBackup() error {
+	inst.bus.publish(newEvent(EventBackupStart, backupPayload{Name: inst.ID}))
+
+	if inst.isRunning() {
+		if err := inst.waitForSave(saveConfirmTimeout); err != nil {
+			inst.bus.publish(newEvent(EventBackupFail, backupPayload{Name: inst.ID, Error: err.Error()}))
+			return err
+		}
+	}
+
+	dir := inst.backupDir()
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		err = fmt.Errorf("creating backup dir: %w", err)
+		inst.bus.publish(newEvent(EventBackupFail, backupPayload{Name: inst.ID, Error: err.Error()}))
+		return err
+	}
+
+	name := fmt.Sprintf("%s-%s.tar.gz", inst.ID, time.Now().UTC().Format("20060102T150405Z"))
+	path := filepath.Join(dir, name)
+
+	size, err := inst.archiveWorldFiles(path)
+	if err != nil {
+		_ = os.Remove(path)
+		inst.bus.publish(newEvent(EventBackupFail, backupPayload{Name: inst.ID, Error: err.Error()}))
+		return err
+	}
+
+	inst.addLog("backup written to " + path)
+	inst.bus.publish(newEvent(EventBackupDone, backupPayload{Name: name, Path: path, Bytes: size}))
+
+	if err := inst.rotateBackups(); err != nil {
+		log.Printf("instance %q: backup rotation failed: %v", inst.ID, err)
+	}
+	return nil
+}
+
+// waitForSave sends the "save" command and blocks until a "World saved"
+// confirmation appears in the instance's log, or timeout elapses.
+func (inst *Instance) waitForSave(timeout time.Duration) error {
+	inst.logMu.Lock()
+	start := len(inst.logs)
+	inst.logMu.Unlock()
+
+	if err := inst.command("save"); err != nil {
+		return fmt.Errorf("sending save command: %w", err)
+	}
+
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		inst.logMu.Lock()
+		newLines := append([]string(nil), inst.logs[start:]...)
+		inst.logMu.Unlock()
+
+		for _, line := range newLines {
+			if strings.Contains(line, saveConfirmLine) {
+				return nil
+			}
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+	return fmt.Errorf("timed out after %s waiting for world save confirmation", timeout)
+}
+
+// archiveWorldFiles writes the instance's world and player files into a
+// tar.gz at destPath and returns the resulting archive size.
+func (inst *Instance) archiveWorldFiles(destPath string) (int64, error) {
+	f, err := os.Create(destPath)
+	if err != nil {
+		return 0, fmt.Errorf("creating archive: %w", err)
+	}
+	defer f.Close()
+
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+
+	worldFiles := inst.worldFiles()
+	if len(worldFiles) == 0 {
+		return 0, fmt.Errorf("instance %q has no world_path configured", inst.ID)
+	}
+
+	// The primary .wld must exist: a backup that silently skips it is worse
+	// than no backup at all. Its paired .twld is optional — older worlds
+	// predate tile entity data and never have one.
+	if err := addFileToTar(tw, worldFiles[0]); err != nil {
+		return 0, fmt.Errorf("archiving world file %q: %w", worldFiles[0], err)
+	}
+	for _, path := range worldFiles[1:] {
+		if err := addFileToTar(tw, path); err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return 0, fmt.Errorf("archiving %q: %w", path, err)
+		}
+	}
+	if inst.PlayersPath != "" {
+		if err := addDirToTar(tw, inst.PlayersPath); err != nil && !os.IsNotExist(err) {
+			return 0, fmt.Errorf("archiving players dir %q: %w", inst.PlayersPath, err)
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return 0, fmt.Errorf("closing archive: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return 0, fmt.Errorf("closing archive: %w", err)
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
+// addFileToTar writes a single file into tw under its base name.
+func addFileToTar(tw *tar.Writer, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	hdr, err := tar.FileInfoHeader(info, "")
+	if err != nil {
+		return err
+	}
+	hdr.Name = filepath.Base(path)
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err = io.Copy(tw, f)
+	return err
+}
+
+// addDirToTar writes every regular file directly inside dir into tw.
+func addDirToTar(tw *tar.Writer, dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		if err := addFileToTar(tw, filepath.Join(dir, e.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// listBackups returns every archive in the instance's backup directory,
+// newest first.
+func (inst *Instance) listBackups() ([]backupFile, error) {
+	dir := inst.backupDir()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	prefix := inst.ID + "-"
+	var backups []backupFile
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasPrefix(e.Name(), prefix) || !strings.HasSuffix(e.Name(), ".tar.gz") {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		backups = append(backups, backupFile{
+			path:    filepath.Join(dir, e.Name()),
+			name:    e.Name(),
+			modTime: info.ModTime(),
+			size:    info.Size(),
+		})
+	}
+	sort.Slice(backups, func(i, j int) bool { return backups[i].modTime.After(backups[j].modTime) })
+	return backups, nil
+}
+
+// rotateBackups keeps the newest keepDaily() archives plus one archive per
+// ISO week for the last keepWeekly() distinct weeks, deleting the rest.
+func (inst *Instance) rotateBackups() error {
+	backups, err := inst.listBackups()
+	if err != nil {
+		return fmt.Errorf("listing backups: %w", err)
+	}
+
+	keep := make(map[string]bool, len(backups))
+	for i, b := range backups {
+		if i < inst.keepDaily() {
+			keep[b.path] = true
+		}
+	}
+
+	seenWeeks := make(map[string]bool)
+	for _, b := range backups {
+		if keep[b.path] {
+			continue
+		}
+		year, week := b.modTime.ISOWeek()
+		key := fmt.Sprintf("%d-%02d", year, week)
+		if seenWeeks[key] {
+			continue
+		}
+		if len(seenWeeks) >= inst.keepWeekly() {
+			continue
+		}
+		seenWeeks[key] = true
+		keep[b.path] = true
+	}
+
+	var firstErr error
+	for _, b := range backups {
+		if keep[b.path] {
+			continue
+		}
+		if err := os.Remove(b.path); err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("removing %q: %w", b.path, err)
+		}
+	}
+	return firstErr
+}
+
+// restoreDestination maps an archive entry name back to the on-disk path it
+// was backed up from.
+func (inst *Instance) restoreDestination(entryName string) string {
+	for _, f := range inst.worldFiles() {
+		if filepath.Base(f) == entryName {
+			return f
+		}
+	}
+	if inst.PlayersPath != "" {
+		return filepath.Join(inst.PlayersPath, entryName)
+	}
+	return ""
+}
+
+// restoreBackup extracts the named archive from the instance's backup
+// directory back over its world and player files. It refuses to run while
+// the instance is up, since overwriting files under a live process would
+// corrupt the running world.
+func (inst *Instance) restoreBackup(name string) error {
+	if inst.isRunning() {
+		return fmt.Errorf("instance %q is running; stop it before restoring a backup", inst.ID)
+	}
+
+	path := filepath.Join(inst.backupDir(), name)
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("opening backup %q: %w", name, err)
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return fmt.Errorf("reading backup %q: %w", name, err)
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("reading backup %q entry: %w", name, err)
+		}
+
+		dest := inst.restoreDestination(hdr.Name)
+		if dest == "" {
+			continue
+		}
+		if err := writeRestoredFile(dest, tr, hdr.FileInfo().Mode()); err != nil {
+			return fmt.Errorf("restoring %q: %w", hdr.Name, err)
+		}
+	}
+
+	inst.addLog("restored backup " + name)
+	return nil
+}
+
+// writeRestoredFile writes the contents read from r to dest, creating its
+// parent directory if needed.
+func writeRestoredFile(dest string, r io.Reader, mode os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+		return err
+	}
+	out, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	_, err = io.Copy(out, r)
+	return err
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRotateBackupsKeepsNewestAndOnePerWeek(t *testing.T) {
+	dir := t.TempDir()
+	inst := newInstance(InstanceConfig{ID: "test", BackupDir: dir, BackupKeepDaily: 2, BackupKeepWeekly: 1})
+
+	now := time.Now()
+	touch := func(name string, age time.Duration) {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte("x"), 0o644); err != nil {
+			t.Fatalf("writing %s: %v", name, err)
+		}
+		mtime := now.Add(-age)
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			t.Fatalf("setting mtime on %s: %v", name, err)
+		}
+	}
+
+	touch("test-1.tar.gz", 0)
+	touch("test-2.tar.gz", 24*time.Hour)
+	touch("test-3.tar.gz", 20*24*time.Hour)
+
+	if err := inst.rotateBackups(); err != nil {
+		t.Fatalf("rotateBackups: %v", err)
+	}
+
+	remaining, err := inst.listBackups()
+	if err != nil {
+		t.Fatalf("listBackups: %v", err)
+	}
+	names := make(map[string]bool, len(remaining))
+	for _, b := range remaining {
+		names[b.name] = true
+	}
+
+	for _, want := range []string{"test-1.tar.gz", "test-2.tar.gz"} {
+		if !names[want] {
+			t.Errorf("expected %s to survive rotation, remaining: %v", want, names)
+		}
+	}
+}
+
+func TestRestoreBackupRefusesWhileRunning(t *testing.T) {
+	inst := newInstance(InstanceConfig{ID: "test"})
+
+	// A Cmd with a nil ProcessState looks "running" to isRunning, with no
+	// need to actually start a child process.
+	inst.cmd = exec.Command("true")
+
+	if err := inst.restoreBackup("test-whatever.tar.gz"); err == nil {
+		t.Fatal("expected restoreBackup to refuse while the instance is running")
+	}
+}
+
+func TestArchiveWorldFilesFailsOnMissingWorld(t *testing.T) {
+	dir := t.TempDir()
+	inst := newInstance(InstanceConfig{ID: "test", WorldPath: filepath.Join(dir, "missing.wld")})
+
+	if _, err := inst.archiveWorldFiles(filepath.Join(dir, "out.tar.gz")); err == nil {
+		t.Fatal("expected archiveWorldFiles to fail when the primary .wld is missing")
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"strings"
+)
+
+// backupInfo is the JSON shape returned by GET /api/backups.
+type backupInfo struct {
+	InstanceID string `json:"instance_id"`
+	Name       string `json:"name"`
+	Bytes      int64  `json:"bytes"`
+	ModTime    int64  `json:"mod_time"`
+}
+
+// backupsHandler serves GET /api/backups and POST /api/backups/{name}/restore.
+// Like instancesHandler, the sub-route is dispatched by hand since the repo
+// has no path-parameter router.
+func backupsHandler(w http.ResponseWriter, r *http.Request) {
+	rest := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/backups"), "/")
+
+	if rest == "" {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		listBackupsHandler(w, r)
+		return
+	}
+
+	parts := strings.SplitN(rest, "/", 2)
+	if len(parts) != 2 || parts[1] != "restore" {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if role, ok := roleFromRequest(r); !ok || role < RoleOperator {
+		http.Error(w, "Insufficient permissions", http.StatusForbidden)
+		return
+	}
+
+	inst, ok := instanceFromQuery(w, r)
+	if !ok {
+		return
+	}
+
+	name := parts[0]
+	if err := manager.RestoreBackup(inst.ID, name); err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	writeJSON(w, summarize(inst))
+}
+
+// listBackupsHandler handles GET /api/backups, optionally filtered to a
+// single instance with ?instance=.
+func listBackupsHandler(w http.ResponseWriter, r *http.Request) {
+	instances := manager.List()
+	if id := r.URL.Query().Get("instance"); id != "" {
+		inst, ok := manager.Get(id)
+		if !ok {
+			http.Error(w, "Unknown instance", http.StatusNotFound)
+			return
+		}
+		instances = []*Instance{inst}
+	}
+
+	var infos []backupInfo
+	for _, inst := range instances {
+		backups, err := inst.listBackups()
+		if err != nil {
+			log.Printf("listing backups for %q: %v", inst.ID, err)
+			continue
+		}
+		for _, b := range backups {
+			infos = append(infos, backupInfo{
+				InstanceID: inst.ID,
+				Name:       b.name,
+				Bytes:      b.size,
+				ModTime:    b.modTime.Unix(),
+			})
+		}
+	}
+	writeJSON(w, infos)
+}
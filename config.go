@@ -0,0 +1,146 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// ResourceLimits caps the host resources a single instance's child process
+// may consume. A zero value means "no limit enforced".
+type ResourceLimits struct {
+	MaxMemoryMB   int `json:"max_memory_mb,omitempty"`
+	MaxCPUPercent int `json:"max_cpu_percent,omitempty"`
+}
+
+// InstanceConfig describes one managed tModLoader server as loaded from the
+// instances config file.
+type InstanceConfig struct {
+	ID             string         `json:"id"`
+	Name           string         `json:"name"`
+	WorldPath      string         `json:"world_path"`
+	Port           int            `json:"port"`
+	ModPack        string         `json:"mod_pack"`
+	ExpectScript   string         `json:"expect_script"`
+	Autostart      bool           `json:"autostart"`
+	ResourceLimits ResourceLimits `json:"resource_limits,omitempty"`
+
+	// PlayersPath is the directory holding per-player data (.plr/.bak files),
+	// included in backups alongside the world files if set.
+	PlayersPath string `json:"players_path,omitempty"`
+	// BackupCron is a robfig/cron spec ("0 */6 * * *") for scheduled
+	// backups. Empty disables scheduled backups for this instance.
+	BackupCron string `json:"backup_cron,omitempty"`
+	// BackupDir is where this instance's backup archives are written.
+	// Defaults to "backups/<id>" if empty.
+	BackupDir string `json:"backup_dir,omitempty"`
+	// BackupKeepDaily and BackupKeepWeekly bound rotation: the newest
+	// BackupKeepDaily archives are always kept, plus one archive per ISO
+	// week for the last BackupKeepWeekly distinct weeks. Zero uses the
+	// package defaults.
+	BackupKeepDaily  int `json:"backup_keep_daily,omitempty"`
+	BackupKeepWeekly int `json:"backup_keep_weekly,omitempty"`
+}
+
+// instancesFile is the on-disk shape of the config file: a flat list of
+// instances under a top-level key, so the file can grow other top-level
+// settings later without breaking the schema.
+type instancesFile struct {
+	Instances []InstanceConfig `json:"instances"`
+}
+
+// LoadInstanceConfigs reads and validates the instance list at path.
+func LoadInstanceConfigs(path string) ([]InstanceConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading instance config %q: %w", path, err)
+	}
+
+	var parsed instancesFile
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		return nil, fmt.Errorf("parsing instance config %q: %w", path, err)
+	}
+
+	seen := make(map[string]bool, len(parsed.Instances))
+	for i := range parsed.Instances {
+		cfg := &parsed.Instances[i]
+		if cfg.ID == "" {
+			return nil, fmt.Errorf("instance config %q: instance missing required \"id\"", path)
+		}
+		if seen[cfg.ID] {
+			return nil, fmt.Errorf("instance config %q: duplicate instance id %q", path, cfg.ID)
+		}
+		seen[cfg.ID] = true
+
+		// WorldPath/PlayersPath/BackupDir are used directly in os calls (no
+		// shell in between to expand them, unlike ExpectScript), so $HOME-style
+		// references in the config would otherwise be taken as literal paths.
+		cfg.WorldPath = os.ExpandEnv(cfg.WorldPath)
+		cfg.PlayersPath = os.ExpandEnv(cfg.PlayersPath)
+		cfg.BackupDir = os.ExpandEnv(cfg.BackupDir)
+	}
+
+	return parsed.Instances, nil
+}
+
+// SaveInstanceConfigs writes configs back to path in the same shape
+// LoadInstanceConfigs reads, so admin config edits persist across restarts.
+func SaveInstanceConfigs(path string, configs []InstanceConfig) error {
+	data, err := json.MarshalIndent(instancesFile{Instances: configs}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding instance config: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("writing instance config %q: %w", path, err)
+	}
+	return nil
+}
+
+// instanceConfigPatch carries admin-editable InstanceConfig fields for
+// PATCH /api/instances/{id}/config. Pointer fields distinguish "omitted"
+// from "set to the zero value". WorldPath/Port/ExpectScript are
+// deliberately not patchable here: changing them under a live instance has
+// sharp edges (a running process, an open port) that deserve their own
+// start/stop-aware handling rather than a blind field overwrite.
+type instanceConfigPatch struct {
+	Name             *string         `json:"name,omitempty"`
+	ModPack          *string         `json:"mod_pack,omitempty"`
+	Autostart        *bool           `json:"autostart,omitempty"`
+	ResourceLimits   *ResourceLimits `json:"resource_limits,omitempty"`
+	PlayersPath      *string         `json:"players_path,omitempty"`
+	BackupCron       *string         `json:"backup_cron,omitempty"`
+	BackupDir        *string         `json:"backup_dir,omitempty"`
+	BackupKeepDaily  *int            `json:"backup_keep_daily,omitempty"`
+	BackupKeepWeekly *int            `json:"backup_keep_weekly,omitempty"`
+}
+
+// apply merges the set fields of p into cfg.
+func (p instanceConfigPatch) apply(cfg *InstanceConfig) {
+	if p.Name != nil {
+		cfg.Name = *p.Name
+	}
+	if p.ModPack != nil {
+		cfg.ModPack = *p.ModPack
+	}
+	if p.Autostart != nil {
+		cfg.Autostart = *p.Autostart
+	}
+	if p.ResourceLimits != nil {
+		cfg.ResourceLimits = *p.ResourceLimits
+	}
+	if p.PlayersPath != nil {
+		cfg.PlayersPath = os.ExpandEnv(*p.PlayersPath)
+	}
+	if p.BackupCron != nil {
+		cfg.BackupCron = *p.BackupCron
+	}
+	if p.BackupDir != nil {
+		cfg.BackupDir = os.ExpandEnv(*p.BackupDir)
+	}
+	if p.BackupKeepDaily != nil {
+		cfg.BackupKeepDaily = *p.BackupKeepDaily
+	}
+	if p.BackupKeepWeekly != nil {
+		cfg.BackupKeepWeekly = *p.BackupKeepWeekly
+	}
+}
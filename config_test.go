@@ -0,0 +1,34 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadInstanceConfigsExpandsEnvInPaths(t *testing.T) {
+	t.Setenv("TMOD_TEST_HOME", "/home/tester")
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "instances.json")
+	body := `{"instances":[{"id":"main","world_path":"$TMOD_TEST_HOME/Worlds/main.wld","players_path":"$TMOD_TEST_HOME/Players","backup_dir":"$TMOD_TEST_HOME/backups"}]}`
+	if err := os.WriteFile(path, []byte(body), 0o644); err != nil {
+		t.Fatalf("writing config: %v", err)
+	}
+
+	configs, err := LoadInstanceConfigs(path)
+	if err != nil {
+		t.Fatalf("LoadInstanceConfigs: %v", err)
+	}
+
+	cfg := configs[0]
+	if cfg.WorldPath != "/home/tester/Worlds/main.wld" {
+		t.Errorf("WorldPath = %q, want expanded $TMOD_TEST_HOME", cfg.WorldPath)
+	}
+	if cfg.PlayersPath != "/home/tester/Players" {
+		t.Errorf("PlayersPath = %q, want expanded $TMOD_TEST_HOME", cfg.PlayersPath)
+	}
+	if cfg.BackupDir != "/home/tester/backups" {
+		t.Errorf("BackupDir = %q, want expanded $TMOD_TEST_HOME", cfg.BackupDir)
+	}
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Event types published on the bus. Every WebSocket frame sent to a client
+// is the JSON encoding of an Event.
+const (
+	EventStatus      = "status"
+	EventLog         = "log"
+	EventPlayerJoin  = "player_join"
+	EventPlayerLeave = "player_leave"
+	EventChat        = "chat"
+	EventStats       = "stats"
+	EventShutdown    = "shutdown"
+	EventBackupStart = "backup_started"
+	EventBackupDone  = "backup_completed"
+	EventBackupFail  = "backup_failed"
+)
+
+// Event is the single typed frame shape sent over the WebSocket protocol.
+type Event struct {
+	Type    string      `json:"type"`
+	Ts      int64       `json:"ts"`
+	Payload interface{} `json:"payload,omitempty"`
+}
+
+// statusPayload is the Payload carried by an EventStatus event.
+type statusPayload struct {
+	Status  string   `json:"status"`
+	Players []string `json:"players"`
+}
+
+// playerPayload is the Payload carried by join/leave events.
+type playerPayload struct {
+	Player string `json:"player"`
+}
+
+// chatPayload is the Payload carried by chat events.
+type chatPayload struct {
+	Player  string `json:"player"`
+	Message string `json:"message"`
+}
+
+// shutdownPayload is the Payload carried by an EventShutdown event.
+type shutdownPayload struct {
+	Message string `json:"message"`
+}
+
+// backupPayload is the Payload carried by EventBackupStart/Done/Fail events.
+type backupPayload struct {
+	Name  string `json:"name,omitempty"`
+	Path  string `json:"path,omitempty"`
+	Bytes int64  `json:"bytes,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// subscriberQueueSize bounds how far a slow client can fall behind before
+// the bus gives up on it rather than blocking every publisher.
+const subscriberQueueSize = 32
+
+// eventBus fans Events out to every registered WebSocket subscriber through
+// a per-client buffered queue, so one slow reader can't stall the others.
+type eventBus struct {
+	mu          sync.Mutex
+	subscribers map[*websocket.Conn]chan Event
+}
+
+func newEventBus() *eventBus {
+	return &eventBus{subscribers: make(map[*websocket.Conn]chan Event)}
+}
+
+// subscribe registers conn and returns the channel it should drain.
+func (b *eventBus) subscribe(conn *websocket.Conn) <-chan Event {
+	ch := make(chan Event, subscriberQueueSize)
+	b.mu.Lock()
+	b.subscribers[conn] = ch
+	b.mu.Unlock()
+	return ch
+}
+
+// unsubscribe removes conn from the bus and closes its queue.
+func (b *eventBus) unsubscribe(conn *websocket.Conn) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if ch, ok := b.subscribers[conn]; ok {
+		delete(b.subscribers, conn)
+		close(ch)
+	}
+}
+
+// publish delivers evt to every subscriber, dropping and disconnecting any
+// client whose queue is already full instead of blocking the caller.
+func (b *eventBus) publish(evt Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for conn, ch := range b.subscribers {
+		select {
+		case ch <- evt:
+		default:
+			log.Printf("event subscriber too slow, dropping: %v", conn.RemoteAddr())
+			delete(b.subscribers, conn)
+			close(ch)
+			go func(c *websocket.Conn) { _ = c.Close() }(conn)
+		}
+	}
+}
+
+// writePump drains ch and writes each Event to conn as JSON until the
+// channel is closed (by unsubscribe) or the write fails.
+func writePump(conn *websocket.Conn, ch <-chan Event) {
+	for evt := range ch {
+		data, err := json.Marshal(evt)
+		if err != nil {
+			log.Println("error marshaling event:", err)
+			continue
+		}
+		if err := conn.WriteMessage(websocket.TextMessage, data); err != nil {
+			return
+		}
+	}
+}
+
+func newEvent(typ string, payload interface{}) Event {
+	return Event{Type: typ, Ts: time.Now().Unix(), Payload: payload}
+}
+
+// Regex matchers for the console lines tModLoader emits for chat and
+// player connection changes.
+var (
+	chatLineRe  = regexp.MustCompile(`^<(\S+)> (.+)$`)
+	joinLineRe  = regexp.MustCompile(`^(\S+) has joined\.?$`)
+	leaveLineRe = regexp.MustCompile(`^(\S+) has left\.?$`)
+)
+
+// parseConsoleLine recognizes player join/leave/chat lines in a raw console
+// line and returns the Event to publish for it, if any.
+func parseConsoleLine(line string) (Event, bool) {
+	if m := chatLineRe.FindStringSubmatch(line); m != nil {
+		return newEvent(EventChat, chatPayload{Player: m[1], Message: m[2]}), true
+	}
+	if m := joinLineRe.FindStringSubmatch(line); m != nil {
+		return newEvent(EventPlayerJoin, playerPayload{Player: m[1]}), true
+	}
+	if m := leaveLineRe.FindStringSubmatch(line); m != nil {
+		return newEvent(EventPlayerLeave, playerPayload{Player: m[1]}), true
+	}
+	return Event{}, false
+}
+
+// playerSet tracks who is currently connected to one instance, kept up to
+// date from parsed join/leave events instead of polling for a player list.
+type playerSet struct {
+	mu      sync.Mutex
+	players map[string]bool
+}
+
+func newPlayerSet() *playerSet {
+	return &playerSet{players: make(map[string]bool)}
+}
+
+func (p *playerSet) add(name string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.players[name] = true
+}
+
+func (p *playerSet) remove(name string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.players, name)
+}
+
+func (p *playerSet) list() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	names := make([]string, 0, len(p.players))
+	for name := range p.players {
+		names = append(names, name)
+	}
+	return names
+}
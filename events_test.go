@@ -0,0 +1,199 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestParseConsoleLine(t *testing.T) {
+	cases := []struct {
+		name string
+		line string
+		want string
+	}{
+		{"join", "Steve has joined.", EventPlayerJoin},
+		{"leave", "Steve has left.", EventPlayerLeave},
+		{"chat", "<Steve> hello world", EventChat},
+		{"unrelated", "Server started", ""},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			evt, ok := parseConsoleLine(tc.line)
+			if tc.want == "" {
+				if ok {
+					t.Fatalf("expected no match for %q, got %+v", tc.line, evt)
+				}
+				return
+			}
+			if !ok {
+				t.Fatalf("expected match for %q", tc.line)
+			}
+			if evt.Type != tc.want {
+				t.Fatalf("got type %q, want %q", evt.Type, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseConsoleLineChatPayload(t *testing.T) {
+	evt, ok := parseConsoleLine("<Steve> hello world")
+	if !ok {
+		t.Fatal("expected match")
+	}
+	payload, ok := evt.Payload.(chatPayload)
+	if !ok {
+		t.Fatalf("unexpected payload type %T", evt.Payload)
+	}
+	if payload.Player != "Steve" || payload.Message != "hello world" {
+		t.Fatalf("got %+v", payload)
+	}
+}
+
+func wsURL(t *testing.T, srv *httptest.Server) string {
+	t.Helper()
+	return "ws" + strings.TrimPrefix(srv.URL, "http")
+}
+
+// TestEventBusFanOutConcurrent publishes events from multiple goroutines
+// while several real WebSocket subscribers drain concurrently, and asserts
+// every subscriber receives events without any publish blocking or panicking.
+func TestEventBusFanOutConcurrent(t *testing.T) {
+	jwtSecret = []byte("test-secret")
+	manager = NewServerManager([]InstanceConfig{{ID: "test"}}, "")
+
+	token, err := signToken("tester", RoleViewer, "", time.Minute)
+	if err != nil {
+		t.Fatalf("signToken: %v", err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(wsHandler))
+	defer srv.Close()
+
+	const numSubscribers = 8
+	const numEvents = 50
+
+	var wg sync.WaitGroup
+	received := make([]int32, numSubscribers)
+	conns := make([]*websocket.Conn, numSubscribers)
+
+	for i := 0; i < numSubscribers; i++ {
+		conn, _, err := websocket.DefaultDialer.Dial(wsURL(t, srv)+"?instance=test&token="+token, nil)
+		if err != nil {
+			t.Fatalf("dial: %v", err)
+		}
+		conns[i] = conn
+
+		wg.Add(1)
+		go func(i int, conn *websocket.Conn) {
+			defer wg.Done()
+			for {
+				if _, _, err := conn.ReadMessage(); err != nil {
+					return
+				}
+				atomic.AddInt32(&received[i], 1)
+			}
+		}(i, conn)
+	}
+
+	// Let the server finish registering each subscriber before publishing.
+	time.Sleep(50 * time.Millisecond)
+
+	inst, _ := manager.Get("test")
+	var pubWg sync.WaitGroup
+	for i := 0; i < numEvents; i++ {
+		pubWg.Add(1)
+		go func() {
+			defer pubWg.Done()
+			inst.bus.publish(newEvent(EventLog, "fan-out test line"))
+		}()
+	}
+	pubWg.Wait()
+
+	time.Sleep(100 * time.Millisecond)
+	for _, conn := range conns {
+		_ = conn.Close()
+	}
+	wg.Wait()
+
+	for i, c := range received {
+		if c == 0 {
+			t.Errorf("subscriber %d received no events", i)
+		}
+	}
+}
+
+// TestWSHandlerAcceptsTokenViaSubprotocol exercises the browser auth path a
+// real `new WebSocket(url, [token])` call takes: the token travels in the
+// Sec-WebSocket-Protocol header, and per RFC 6455 the server must echo a
+// subprotocol back or the client-side handshake fails even though the token
+// itself is valid.
+func TestWSHandlerAcceptsTokenViaSubprotocol(t *testing.T) {
+	jwtSecret = []byte("test-secret")
+	manager = NewServerManager([]InstanceConfig{{ID: "test"}}, "")
+
+	token, err := signToken("tester", RoleViewer, "", time.Minute)
+	if err != nil {
+		t.Fatalf("signToken: %v", err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(wsHandler))
+	defer srv.Close()
+
+	dialer := websocket.Dialer{Subprotocols: []string{token}}
+	conn, resp, err := dialer.Dial(wsURL(t, srv)+"?instance=test", nil)
+	if err != nil {
+		t.Fatalf("dial with subprotocol token: %v", err)
+	}
+	defer conn.Close()
+
+	if got := resp.Header.Get("Sec-WebSocket-Protocol"); got != token {
+		t.Errorf("Sec-WebSocket-Protocol response header = %q, want echoed token %q", got, token)
+	}
+}
+
+// TestEventBusDropsSlowSubscriber verifies that a subscriber whose queue
+// fills up (because nothing drains it) is dropped rather than stalling
+// publish for everyone else.
+func TestEventBusDropsSlowSubscriber(t *testing.T) {
+	b := newEventBus()
+
+	connCh := make(chan *websocket.Conn, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		b.subscribe(conn) // deliberately never drained
+		connCh <- conn
+		<-r.Context().Done()
+	}))
+	defer srv.Close()
+
+	client, _, err := websocket.DefaultDialer.Dial(wsURL(t, srv), nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer func() { _ = client.Close() }()
+
+	serverConn := <-connCh
+
+	for i := 0; i < subscriberQueueSize+10; i++ {
+		b.publish(newEvent(EventLog, "overflow line"))
+	}
+
+	b.mu.Lock()
+	_, stillSubscribed := b.subscribers[serverConn]
+	b.mu.Unlock()
+
+	if stillSubscribed {
+		t.Fatal("expected slow subscriber to be dropped once its queue filled")
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"net/http"
+	"time"
+)
+
+// pumpFreshness bounds how long an instance's pty pump may go without a new
+// log line before /readyz considers it stalled.
+const pumpFreshness = 30 * time.Second
+
+// healthzHandler reports whether the manager process itself is alive. It
+// never depends on any instance's state, so a single stuck server doesn't
+// take the whole manager out of a load balancer's rotation.
+func healthzHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, map[string]string{"status": "ok"})
+}
+
+// readyzHandler reports whether an instance (or, with no ?instance= query,
+// every running instance) is actually serving: the child process is up and
+// its pty pump has produced a log line within pumpFreshness. A running
+// process whose pump has gone quiet usually means the pty died without the
+// process noticing yet.
+func readyzHandler(w http.ResponseWriter, r *http.Request) {
+	if id := r.URL.Query().Get("instance"); id != "" {
+		inst, ok := manager.Get(id)
+		if !ok {
+			http.Error(w, "Unknown instance", http.StatusNotFound)
+			return
+		}
+		if !instanceReady(inst) {
+			http.Error(w, "not ready", http.StatusServiceUnavailable)
+			return
+		}
+		writeJSON(w, map[string]string{"status": "ready"})
+		return
+	}
+
+	for _, inst := range manager.List() {
+		if inst.isRunning() && !instanceReady(inst) {
+			http.Error(w, "not ready", http.StatusServiceUnavailable)
+			return
+		}
+	}
+	writeJSON(w, map[string]string{"status": "ready"})
+}
+
+// instanceReady reports whether inst is running and its pump has produced a
+// log line recently enough to trust it's still attached to the process.
+func instanceReady(inst *Instance) bool {
+	return inst.isRunning() && time.Since(inst.lastActivity()) < pumpFreshness
+}
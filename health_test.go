@@ -0,0 +1,33 @@
+package main
+
+import "testing"
+
+func TestInstanceReadyRequiresRunningAndFreshPump(t *testing.T) {
+	inst := newInstance(InstanceConfig{ID: "idle"})
+
+	if instanceReady(inst) {
+		t.Fatal("expected a never-started instance to not be ready")
+	}
+}
+
+func TestCommandLabelFallsBackToUnknown(t *testing.T) {
+	if got := commandLabel("   "); got != "unknown" {
+		t.Fatalf("commandLabel(whitespace) = %q, want \"unknown\"", got)
+	}
+	if got := commandLabel("save all"); got != "save" {
+		t.Fatalf("commandLabel(\"save all\") = %q, want \"save\"", got)
+	}
+}
+
+func TestLogLevelClassifiesKnownSubstrings(t *testing.T) {
+	cases := map[string]string{
+		"Server started":          "info",
+		"WARNING: low disk space": "warn",
+		"ERROR: failed to bind":   "error",
+	}
+	for line, want := range cases {
+		if got := logLevel(line); got != want {
+			t.Errorf("logLevel(%q) = %q, want %q", line, got, want)
+		}
+	}
+}
@@ -0,0 +1,276 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+
+	"github.com/creack/pty"
+	"github.com/gorilla/websocket"
+)
+
+// Instance manages a single named tModLoader server: its child process, its
+// own event bus, console subscribers, log ring buffer and player list. Each
+// instance is independent so the manager can run several side by side.
+type Instance struct {
+	InstanceConfig
+
+	mu   sync.Mutex
+	cmd  *exec.Cmd
+	ptmx *os.File
+
+	logMu   sync.Mutex
+	logs    []string
+	lastLog time.Time
+	bus     *eventBus
+	players *playerSet
+
+	consoleMu      sync.Mutex
+	consoleClients map[*websocket.Conn]bool
+}
+
+// maxRingLogs bounds how many lines an instance keeps in memory.
+const maxRingLogs = 500
+
+func newInstance(cfg InstanceConfig) *Instance {
+	return &Instance{
+		InstanceConfig: cfg,
+		bus:            newEventBus(),
+		players:        newPlayerSet(),
+		consoleClients: make(map[*websocket.Conn]bool),
+	}
+}
+
+// isRunning reports whether the instance's child process is alive.
+func (inst *Instance) isRunning() bool {
+	inst.mu.Lock()
+	defer inst.mu.Unlock()
+	return inst.cmd != nil && inst.cmd.ProcessState == nil
+}
+
+// start launches the instance's expect script under a pty and begins
+// pumping its output. Callers must serialize calls per instance (the
+// manager does this via its per-instance lock).
+func (inst *Instance) start() error {
+	if inst.isRunning() {
+		return fmt.Errorf("instance %q already running", inst.ID)
+	}
+
+	cmd := exec.Command("bash", "-c", inst.ExpectScript)
+	ptmx, err := pty.Start(cmd)
+	if err != nil {
+		startFailuresTotal.WithLabelValues(inst.ID).Inc()
+		return fmt.Errorf("starting instance %q: %w", inst.ID, err)
+	}
+
+	inst.mu.Lock()
+	inst.cmd = cmd
+	inst.ptmx = ptmx
+	inst.mu.Unlock()
+
+	go inst.pump(ptmx)
+
+	inst.addLog("instance started at " + time.Now().Format(time.RFC1123))
+	inst.publishStatus()
+	return nil
+}
+
+// stop sends the server's exit command and lets the pty pump's scanner loop
+// detect process exit and clean up.
+func (inst *Instance) stop() error {
+	if !inst.isRunning() {
+		return fmt.Errorf("instance %q not running", inst.ID)
+	}
+	if err := inst.command("exit"); err != nil {
+		return fmt.Errorf("stopping instance %q: %w", inst.ID, err)
+	}
+	inst.addLog("instance stop requested at " + time.Now().Format(time.RFC1123))
+	return nil
+}
+
+// restart stops the instance and starts it again once the old process has
+// fully exited.
+func (inst *Instance) restart() error {
+	if inst.isRunning() {
+		if err := inst.stop(); err != nil {
+			return err
+		}
+		for i := 0; i < 100 && inst.isRunning(); i++ {
+			time.Sleep(100 * time.Millisecond)
+		}
+	}
+	return inst.start()
+}
+
+// shutdown tells the instance to save its world and exit, waiting up to
+// grace for it to stop on its own before escalating to killing the process
+// outright. It is a no-op if the instance isn't running.
+func (inst *Instance) shutdown(grace time.Duration) {
+	if !inst.isRunning() {
+		return
+	}
+
+	inst.bus.publish(newEvent(EventShutdown, shutdownPayload{Message: "server is shutting down"}))
+	inst.addLog("shutdown requested, saving world")
+	log.Printf("instance %q: saving world before shutdown", inst.ID)
+	if err := inst.command("save"); err != nil {
+		log.Printf("instance %q: failed to send save command: %v", inst.ID, err)
+	}
+	// Give the save command a moment to start before asking the process to
+	// exit, since "save" and "exit" both go through the same stdin queue.
+	time.Sleep(2 * time.Second)
+
+	log.Printf("instance %q: requesting exit", inst.ID)
+	if err := inst.command("exit"); err != nil {
+		log.Printf("instance %q: failed to send exit command: %v", inst.ID, err)
+	}
+
+	deadline := time.Now().Add(grace)
+	for inst.isRunning() && time.Now().Before(deadline) {
+		time.Sleep(200 * time.Millisecond)
+	}
+
+	if inst.isRunning() {
+		log.Printf("instance %q: did not exit within %s, killing process", inst.ID, grace)
+		inst.kill()
+		return
+	}
+	log.Printf("instance %q: exited cleanly during shutdown", inst.ID)
+}
+
+// kill forcibly terminates the instance's child process.
+func (inst *Instance) kill() {
+	inst.mu.Lock()
+	cmd := inst.cmd
+	inst.mu.Unlock()
+
+	if cmd == nil || cmd.Process == nil {
+		return
+	}
+	if err := cmd.Process.Kill(); err != nil {
+		log.Printf("instance %q: failed to kill process: %v", inst.ID, err)
+	}
+}
+
+// command forwards an operator-typed line to the instance's stdin.
+func (inst *Instance) command(line string) error {
+	inst.mu.Lock()
+	ptmx := inst.ptmx
+	inst.mu.Unlock()
+
+	if ptmx == nil {
+		return io.ErrClosedPipe
+	}
+	commandsSentTotal.WithLabelValues(commandLabel(line)).Inc()
+	_, err := ptmx.Write([]byte(line + "\n"))
+	return err
+}
+
+// pump scans the pty's combined stdout/stderr line by line, records and
+// broadcasts each line, and feeds it to the parser so player join/leave/chat
+// activity is picked up continuously instead of via periodic polling.
+func (inst *Instance) pump(ptmx *os.File) {
+	scanner := bufio.NewScanner(ptmx)
+	for scanner.Scan() {
+		line := scanner.Text()
+		inst.addLog(line)
+		inst.broadcastConsoleLine(line)
+
+		if evt, ok := parseConsoleLine(line); ok {
+			if payload, ok := evt.Payload.(playerPayload); ok {
+				if evt.Type == EventPlayerJoin {
+					inst.players.add(payload.Player)
+				} else {
+					inst.players.remove(payload.Player)
+				}
+				inst.publishStatus()
+			}
+			inst.bus.publish(evt)
+		}
+	}
+	if err := scanner.Err(); err != nil && err != io.EOF {
+		log.Printf("instance %q pty read error: %v", inst.ID, err)
+	}
+
+	inst.mu.Lock()
+	inst.cmd = nil
+	inst.ptmx = nil
+	inst.mu.Unlock()
+
+	inst.addLog("instance exited at " + time.Now().Format(time.RFC1123))
+	inst.publishStatus()
+}
+
+// addLog records a new log entry and publishes it as an EventLog frame.
+func (inst *Instance) addLog(entry string) {
+	line := fmt.Sprintf("[%s] %s", time.Now().Format("15:04:05"), entry)
+
+	inst.logMu.Lock()
+	inst.logs = append(inst.logs, line)
+	if len(inst.logs) > maxRingLogs {
+		inst.logs = inst.logs[len(inst.logs)-maxRingLogs:]
+	}
+	inst.lastLog = time.Now()
+	inst.logMu.Unlock()
+
+	logLinesTotal.WithLabelValues(logLevel(entry)).Inc()
+	inst.bus.publish(newEvent(EventLog, line))
+}
+
+// lastActivity returns when addLog was last called for this instance, used
+// by /readyz to tell a healthy pty pump from one that's stopped receiving
+// output without the process actually dying.
+func (inst *Instance) lastActivity() time.Time {
+	inst.logMu.Lock()
+	defer inst.logMu.Unlock()
+	return inst.lastLog
+}
+
+// publishStatus publishes the instance's current status and player list as
+// an EventStatus frame to every subscriber on its bus.
+func (inst *Instance) publishStatus() {
+	payload := statusPayload{Status: "stopped", Players: []string{}}
+	up := 0.0
+	if inst.isRunning() {
+		payload.Status = "running"
+		payload.Players = inst.players.list()
+		up = 1
+	}
+	serverUp.WithLabelValues(inst.ID).Set(up)
+	playersOnline.WithLabelValues(inst.ID).Set(float64(len(payload.Players)))
+	inst.bus.publish(newEvent(EventStatus, payload))
+}
+
+// broadcastConsoleLine sends a single raw console line to every subscribed
+// /console client, dropping and closing any connection that can't keep up.
+func (inst *Instance) broadcastConsoleLine(line string) {
+	inst.consoleMu.Lock()
+	defer inst.consoleMu.Unlock()
+
+	for conn := range inst.consoleClients {
+		_ = conn.SetWriteDeadline(time.Now().Add(consoleWriteWait))
+		if err := conn.WriteMessage(websocket.TextMessage, []byte(line)); err != nil {
+			log.Printf("instance %q console write failed, dropping client: %v", inst.ID, err)
+			delete(inst.consoleClients, conn)
+			_ = conn.Close()
+		}
+	}
+}
+
+func (inst *Instance) addConsoleClient(conn *websocket.Conn) {
+	inst.consoleMu.Lock()
+	defer inst.consoleMu.Unlock()
+	inst.consoleClients[conn] = true
+}
+
+func (inst *Instance) removeConsoleClient(conn *websocket.Conn) {
+	inst.consoleMu.Lock()
+	defer inst.consoleMu.Unlock()
+	delete(inst.consoleClients, conn)
+	_ = conn.Close()
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestInstanceStartStop(t *testing.T) {
+	inst := newInstance(InstanceConfig{ID: "test", ExpectScript: "sleep 2"})
+
+	if err := inst.start(); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	if !inst.isRunning() {
+		t.Fatal("expected instance to be running after start")
+	}
+	if err := inst.start(); err == nil {
+		t.Fatal("expected start to fail while already running")
+	}
+
+	if err := inst.stop(); err != nil {
+		t.Fatalf("stop: %v", err)
+	}
+
+	inst.kill()
+	for i := 0; i < 50 && inst.isRunning(); i++ {
+		time.Sleep(20 * time.Millisecond)
+	}
+	if inst.isRunning() {
+		t.Fatal("expected instance to stop running after kill")
+	}
+	if err := inst.stop(); err == nil {
+		t.Fatal("expected stop to fail while not running")
+	}
+}
+
+func TestInstanceRestartStartsAFreshProcess(t *testing.T) {
+	inst := newInstance(InstanceConfig{ID: "test", ExpectScript: "sleep 2"})
+
+	if err := inst.start(); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	first := inst.cmd.Process.Pid
+
+	if err := inst.restart(); err != nil {
+		t.Fatalf("restart: %v", err)
+	}
+	if !inst.isRunning() {
+		t.Fatal("expected instance to be running after restart")
+	}
+	if inst.cmd.Process.Pid == first {
+		t.Fatal("expected restart to launch a new process")
+	}
+
+	inst.kill()
+}
+
+func TestInstanceShutdownNoopWhenNotRunning(t *testing.T) {
+	inst := newInstance(InstanceConfig{ID: "idle"})
+
+	// Must not block or panic: there is no process to save/exit/kill.
+	inst.shutdown(0)
+
+	if inst.isRunning() {
+		t.Fatal("expected idle instance to remain not running")
+	}
+}
+
+func TestInstanceKillNoopWithoutProcess(t *testing.T) {
+	inst := newInstance(InstanceConfig{ID: "idle"})
+
+	// Must not panic when there's no child process to kill.
+	inst.kill()
+}
@@ -1,67 +1,81 @@
 package main
 
 import (
-	"bufio"
 	"context"
-	"encoding/json"
 	"errors"
-	"fmt"
+	"flag"
 	"log"
 	"net/http"
 	"os"
-	"os/exec"
 	"os/signal"
-	"strings"
-	"sync"
 	"syscall"
 	"time"
 
 	"github.com/gorilla/websocket"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
-// Configuration constants
-const (
-	screenName     = "tmod_session"                             // Name of the screen session for the Terraria server
-	expectScript   = "$HOME/Desktop/scripts/tmod_server.expect" // Path to the expect script that starts the server
-	hardcopyOutput = "/tmp/tmod_screen_out.txt"                 // Path to save screen output
-)
-
-// Global variables
-var (
-	logs      []string                         // Stores server logs
-	logMutex  sync.Mutex                       // Mutex to protect concurrent access to logs
-	upgrader  = websocket.Upgrader{}           // WebSocket upgrader for HTTP to WebSocket protocol
-	clients   = make(map[*websocket.Conn]bool) // Map of active WebSocket connections
-	clientsMu sync.Mutex                       // Mutex to protect concurrent access to clients map
-)
+// upgrader is shared by every WebSocket endpoint.
+var upgrader = websocket.Upgrader{}
 
-// WSMessage defines the structure for WebSocket messages
-type WSMessage struct {
-	Status  string   `json:"status"`         // Server status (running/stopped)
-	Players []string `json:"players"`        // List of currently connected players
-	Logs    []string `json:"logs,omitempty"` // Recent server logs
-}
+// manager owns every configured instance for the lifetime of the process.
+var manager *ServerManager
 
-// main initializes the server and sets up HTTP routes
+// main loads the instance config and user store, starts any autostart
+// instances, and serves the HTTP/WebSocket API until it receives a
+// shutdown signal.
 func main() {
-	// Start a goroutine to periodically check server status
-	go monitorServerStatus()
+	configPath := flag.String("config", "instances.json", "path to the instances config file")
+	usersPath := flag.String("users", "users.json", "path to the bcrypt-hashed user file")
+	shutdownGrace := flag.Duration("shutdown-grace", 30*time.Second, "how long to wait for an instance to save and exit before killing it")
+	flag.Parse()
+
+	jwtSecret = []byte(os.Getenv("TMOD_JWT_SECRET"))
+	if len(jwtSecret) == 0 {
+		log.Fatal("TMOD_JWT_SECRET must be set to a random signing secret")
+	}
+
+	var err error
+	users, err = loadUserStore(*usersPath)
+	if err != nil {
+		log.Fatalf("loading user store: %v", err)
+	}
+
+	configs, err := LoadInstanceConfigs(*configPath)
+	if err != nil {
+		log.Fatalf("loading instance config: %v", err)
+	}
+
+	manager = NewServerManager(configs, *configPath)
+	manager.AutostartAll()
 
-	// Set up HTTP routes without authentication middleware
-	http.HandleFunc("/", indexHandler)      // Serve index page
-	http.HandleFunc("/ws", wsHandler)       // WebSocket endpoint
-	http.HandleFunc("/start", startHandler) // Start server endpoint
-	http.HandleFunc("/stop", stopHandler)   // Stop server endpoint
+	scheduler := NewBackupScheduler(manager)
+	for _, inst := range manager.List() {
+		if err := scheduler.Schedule(inst); err != nil {
+			log.Printf("invalid backup_cron for instance %q: %v", inst.ID, err)
+		}
+	}
+	scheduler.Start()
+
+	http.HandleFunc("/", indexHandler)                                            // Serve index page
+	http.HandleFunc("/login", loginHandler)                                       // Issue access/refresh/CSRF tokens
+	http.HandleFunc("/refresh", refreshHandler)                                   // Exchange a refresh token for a new access token
+	http.HandleFunc("/ws", wsHandler)                                             // Per-instance WebSocket event stream (viewer+)
+	http.HandleFunc("/console", consoleHandler)                                   // Per-instance interactive console WebSocket (operator+)
+	http.HandleFunc("/api/instances", requireRole(RoleViewer, instancesHandler))  // List / per-instance control
+	http.HandleFunc("/api/instances/", requireRole(RoleViewer, instancesHandler)) // Sub-routes: start/stop/restart/command/config
+	http.HandleFunc("/api/backups", requireRole(RoleViewer, backupsHandler))      // List available archives
+	http.HandleFunc("/api/backups/", requireRole(RoleViewer, backupsHandler))     // Sub-route: restore
+	http.HandleFunc("/healthz", healthzHandler)                                   // Process liveness, unauthenticated
+	http.HandleFunc("/readyz", readyzHandler)                                     // Instance readiness, unauthenticated
+	http.Handle("/metrics", promhttp.Handler())                                   // Prometheus scrape endpoint
 	// Serve static files from web directory
 	http.Handle("/static/", http.StripPrefix("/static/", http.FileServer(http.Dir("./web/static"))))
 
 	log.Println("Server started at http://kwf-go.online")
-	// Define the HTTP server
 	srv := &http.Server{Addr: ":8080", Handler: nil}
 
-	// Start the server in a goroutine
 	go func() {
-		log.Println("Server started at http://kwf-go.online")
 		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
 			log.Fatalf("Listen error: %s\n", err)
 		}
@@ -73,6 +87,12 @@ func main() {
 	<-quit
 	log.Println("Shutting down server...")
 
+	scheduler.Stop()
+
+	log.Printf("stopping all running instances (grace period %s)", *shutdownGrace)
+	manager.ShutdownAll(*shutdownGrace)
+	log.Println("all instances stopped")
+
 	// Shutdown with timeout context
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
@@ -82,7 +102,6 @@ func main() {
 	}
 
 	log.Println("Server exited cleanly.")
-
 }
 
 // indexHandler serves the main HTML page
@@ -90,252 +109,3 @@ func indexHandler(w http.ResponseWriter, r *http.Request) {
 	log.Println("Received request for index page") // Log when this handler is called
 	http.ServeFile(w, r, "./web/index.html")
 }
-
-// startHandler handles requests to start the Terraria server
-func startHandler(w http.ResponseWriter, r *http.Request) {
-	// Ensure endpoint only accepts POST requests
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
-	// Check if server is already running
-	if isServerRunning() {
-		http.Error(w, "Server already running", http.StatusConflict)
-		return
-	}
-
-	// Start the Terraria server in a new screen session
-	cmd := exec.Command("screen", "-S", screenName, "-dm", "bash", "-c", expectScript)
-	if err := cmd.Run(); err != nil {
-		http.Error(w, "Failed to start server", http.StatusInternalServerError)
-		addLog("Failed to start server: " + err.Error())
-		return
-	}
-
-	// Log successful start and broadcast status to clients
-	addLog("Server started at " + time.Now().Format(time.RFC1123))
-	broadcastStatus()
-}
-
-// stopHandler handles requests to stop the Terraria server
-func stopHandler(w http.ResponseWriter, r *http.Request) {
-	// Ensure endpoint only accepts POST requests
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
-	// Check if server is running
-	if !isServerRunning() {
-		http.Error(w, "Server not running", http.StatusConflict)
-		return
-	}
-
-	// Send exit command to the server
-	sendScreenCommand("exit")
-
-	// Log successful stop and broadcast status to clients
-	addLog("Server stopped at " + time.Now().Format(time.RFC1123))
-	broadcastStatus()
-}
-
-// isServerRunning checks if the server screen session exists
-func isServerRunning() bool {
-	cmd := exec.Command("screen", "-list")
-	out, err := cmd.Output()
-	if err != nil {
-		return false
-	}
-	// Check if the screen session name is found in the output
-	return strings.Contains(string(out), screenName)
-}
-
-// sendScreenCommand sends a command to the screen session
-func sendScreenCommand(command string) {
-	err := exec.Command("screen", "-S", screenName, "-X", "stuff", command+"\n").Run()
-	if err != nil {
-		fmt.Println("Could not send screen command")
-		return
-	}
-}
-
-// broadcastStatus sends current server status to all connected WebSocket clients
-func broadcastStatus() {
-	// Lock to prevent concurrent map access
-	clientsMu.Lock()
-	defer clientsMu.Unlock()
-
-	// Prepare message with default "stopped" status
-	msg := WSMessage{
-		Status:  "stopped",
-		Players: []string{},
-		Logs:    getLastLogs(),
-	}
-
-	// Update message if server is running
-	if isServerRunning() {
-		msg.Status = "running"
-		msg.Players = getCurrentPlayers()
-	}
-
-	// Marshal message to JSON
-	data, err := json.Marshal(msg)
-	if err != nil {
-		log.Println("Error marshaling WebSocket message:", err)
-		return
-	}
-
-	// Send message to all clients
-	for client := range clients {
-		if client == nil {
-			continue
-		}
-		if err := client.WriteMessage(websocket.TextMessage, data); err != nil {
-			log.Printf("Error sending message to client: %v", err)
-
-			// Remove and close the faulty client connection
-			delete(clients, client)
-			if cerr := client.Close(); cerr != nil {
-				log.Printf("Error closing WebSocket client: %v", cerr)
-			}
-		}
-	}
-}
-
-// getLastLogs returns the most recent log entries (up to 10)
-func getLastLogs() []string {
-	logMutex.Lock()
-	defer logMutex.Unlock()
-
-	// Return all logs if fewer than 10
-	if len(logs) <= 10 {
-		return logs
-	}
-	// Otherwise return the last 10 logs
-	return logs[len(logs)-10:]
-}
-
-// addLog adds a new log entry with timestamp
-func addLog(entry string) {
-	logMutex.Lock()
-	defer logMutex.Unlock()
-
-	// Format log entry with timestamp
-	logs = append(logs, fmt.Sprintf("[%s] %s", time.Now().Format("15:04:05"), entry))
-}
-
-// wsHandler handles WebSocket connections
-func wsHandler(w http.ResponseWriter, r *http.Request) {
-	// Upgrade HTTP connection to WebSocket
-	conn, err := upgrader.Upgrade(w, r, nil)
-	if err != nil {
-		log.Println("WS upgrade failed:", err)
-		return
-	}
-
-	// Ensure connection is closed when handler exits
-	defer func() {
-		if err := conn.Close(); err != nil {
-			log.Printf("Error closing WebSocket connection: %v", err)
-		}
-	}()
-
-	// Add client to active clients map
-	clientsMu.Lock()
-	clients[conn] = true
-	clientsMu.Unlock()
-
-	// Keep connection open and handle incoming messages
-	for {
-		_, _, err := conn.ReadMessage()
-		if err != nil {
-			break // Exit loop on read error (client disconnect)
-		}
-	}
-
-	// Remove client from map when disconnected
-	clientsMu.Lock()
-	delete(clients, conn)
-	clientsMu.Unlock()
-}
-
-// monitorServerStatus periodically checks server status and updates clients
-func monitorServerStatus() {
-	for {
-		// Save screen output if server is running
-		if isServerRunning() {
-			saveScreenOutput()
-		}
-
-		// Broadcast current status to all clients
-		broadcastStatus()
-
-		// Wait before next check
-		time.Sleep(5 * time.Second)
-	}
-}
-
-// saveScreenOutput saves the current screen content to a file
-func saveScreenOutput() {
-	err := exec.Command("screen", "-S", screenName, "-X", "hardcopy", hardcopyOutput).Run()
-	if err != nil {
-		fmt.Println("Could not save screen")
-	}
-}
-
-// getCurrentPlayers retrieves the list of currently connected players
-func getCurrentPlayers() []string {
-	// Send the 'players' command to the server
-	sendScreenCommand("players")
-
-	// Wait for command to execute
-	time.Sleep(2 * time.Second)
-
-	// Save output to file
-	saveScreenOutput()
-
-	// Open the output file
-	file, err := os.Open(hardcopyOutput)
-	if err != nil {
-		return []string{}
-	}
-	defer func() {
-		if err := file.Close(); err != nil {
-			log.Printf("Error closing file: %v", err)
-		}
-	}()
-
-	// Read file line by line
-	scanner := bufio.NewScanner(file)
-	var lines []string
-	for scanner.Scan() {
-		lines = append(lines, scanner.Text())
-	}
-
-	// Search for player list from bottom up (most recent first)
-	for i := len(lines) - 1; i >= 0; i-- {
-		if strings.HasPrefix(lines[i], "Current players") || strings.HasPrefix(lines[i], "Players:") {
-			return parsePlayersLine(lines[i])
-		}
-	}
-	return []string{}
-}
-
-// parsePlayersLine extracts player names from a line of output
-func parsePlayersLine(line string) []string {
-	if strings.Contains(line, ":") {
-		// Split line at first colon
-		parts := strings.SplitN(line, ":", 2)
-		if len(parts) > 1 {
-			raw := strings.TrimSpace(parts[1])
-			// Return empty list if no players
-			if raw == "None" || raw == "" {
-				return []string{}
-			}
-			// Split comma-separated list of players
-			return strings.Split(raw, ", ")
-		}
-	}
-	return []string{}
-}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ServerManager owns every configured Instance and serializes start/stop/
+// restart operations per instance so two requests for the same server can
+// never race each other.
+type ServerManager struct {
+	instances  map[string]*Instance
+	locks      sync.Map // instance ID -> *sync.Mutex
+	configPath string
+}
+
+// NewServerManager builds a manager for the given instance configs. It does
+// not start anything; call AutostartAll once the manager is wired up.
+// configPath is the file UpdateConfig persists edits back to.
+func NewServerManager(configs []InstanceConfig, configPath string) *ServerManager {
+	m := &ServerManager{instances: make(map[string]*Instance, len(configs)), configPath: configPath}
+	for _, cfg := range configs {
+		m.instances[cfg.ID] = newInstance(cfg)
+	}
+	return m
+}
+
+// Get looks up an instance by ID.
+func (m *ServerManager) Get(id string) (*Instance, bool) {
+	inst, ok := m.instances[id]
+	return inst, ok
+}
+
+// List returns every managed instance, ordered by ID for stable output.
+func (m *ServerManager) List() []*Instance {
+	instances := make([]*Instance, 0, len(m.instances))
+	for _, inst := range m.instances {
+		instances = append(instances, inst)
+	}
+	sort.Slice(instances, func(i, j int) bool { return instances[i].ID < instances[j].ID })
+	return instances
+}
+
+// lockFor returns the mutex that serializes start/stop/restart for id,
+// creating one on first use.
+func (m *ServerManager) lockFor(id string) *sync.Mutex {
+	l, _ := m.locks.LoadOrStore(id, &sync.Mutex{})
+	return l.(*sync.Mutex)
+}
+
+// Start starts the named instance, failing if it is already running.
+func (m *ServerManager) Start(id string) error {
+	inst, ok := m.Get(id)
+	if !ok {
+		return fmt.Errorf("unknown instance %q", id)
+	}
+	lock := m.lockFor(id)
+	lock.Lock()
+	defer lock.Unlock()
+	return inst.start()
+}
+
+// Stop stops the named instance, failing if it is not running.
+func (m *ServerManager) Stop(id string) error {
+	inst, ok := m.Get(id)
+	if !ok {
+		return fmt.Errorf("unknown instance %q", id)
+	}
+	lock := m.lockFor(id)
+	lock.Lock()
+	defer lock.Unlock()
+	return inst.stop()
+}
+
+// Restart stops and starts the named instance.
+func (m *ServerManager) Restart(id string) error {
+	inst, ok := m.Get(id)
+	if !ok {
+		return fmt.Errorf("unknown instance %q", id)
+	}
+	lock := m.lockFor(id)
+	lock.Lock()
+	defer lock.Unlock()
+	return inst.restart()
+}
+
+// Command sends a raw console command to the named instance.
+func (m *ServerManager) Command(id, line string) error {
+	inst, ok := m.Get(id)
+	if !ok {
+		return fmt.Errorf("unknown instance %q", id)
+	}
+	return inst.command(line)
+}
+
+// UpdateConfig applies patch to the named instance's config and persists the
+// full instance list back to configPath. It refuses to run while the
+// instance is up, since several patchable fields (backup paths, resource
+// limits) only take effect on next start and silently editing them under a
+// live process would be misleading.
+func (m *ServerManager) UpdateConfig(id string, patch instanceConfigPatch) (InstanceConfig, error) {
+	inst, ok := m.Get(id)
+	if !ok {
+		return InstanceConfig{}, fmt.Errorf("unknown instance %q", id)
+	}
+	lock := m.lockFor(id)
+	lock.Lock()
+	defer lock.Unlock()
+
+	if inst.isRunning() {
+		return InstanceConfig{}, fmt.Errorf("instance %q is running; stop it before editing its config", id)
+	}
+
+	patch.apply(&inst.InstanceConfig)
+
+	configs := make([]InstanceConfig, 0, len(m.instances))
+	for _, i := range m.List() {
+		configs = append(configs, i.InstanceConfig)
+	}
+	if err := SaveInstanceConfigs(m.configPath, configs); err != nil {
+		return InstanceConfig{}, fmt.Errorf("persisting instance config: %w", err)
+	}
+	return inst.InstanceConfig, nil
+}
+
+// RunBackup runs a backup for the named instance, serialized with Start/
+// Stop/Restart/UpdateConfig so a scheduled backup can never overlap an
+// operator-triggered lifecycle change on the same instance.
+func (m *ServerManager) RunBackup(id string) error {
+	inst, ok := m.Get(id)
+	if !ok {
+		return fmt.Errorf("unknown instance %q", id)
+	}
+	lock := m.lockFor(id)
+	lock.Lock()
+	defer lock.Unlock()
+	return inst.runBackup()
+}
+
+// RestoreBackup restores the named archive onto the named instance,
+// serialized with Start/Stop/Restart/UpdateConfig so a concurrent Start
+// can't slip in between restoreBackup's running check and its file
+// overwrites.
+func (m *ServerManager) RestoreBackup(id, name string) error {
+	inst, ok := m.Get(id)
+	if !ok {
+		return fmt.Errorf("unknown instance %q", id)
+	}
+	lock := m.lockFor(id)
+	lock.Lock()
+	defer lock.Unlock()
+	return inst.restoreBackup(name)
+}
+
+// ShutdownAll tells every running instance to save and exit, waiting up to
+// grace for each one before escalating to a kill, and returns once every
+// instance has settled. Instances shut down concurrently so one slow world
+// save doesn't hold up the others.
+func (m *ServerManager) ShutdownAll(grace time.Duration) {
+	var wg sync.WaitGroup
+	for _, inst := range m.List() {
+		if !inst.isRunning() {
+			continue
+		}
+		wg.Add(1)
+		go func(inst *Instance) {
+			defer wg.Done()
+			lock := m.lockFor(inst.ID)
+			lock.Lock()
+			defer lock.Unlock()
+			inst.shutdown(grace)
+		}(inst)
+	}
+	wg.Wait()
+}
+
+// AutostartAll starts every instance configured with Autostart, logging
+// rather than failing if one can't come up so the others still get a try.
+func (m *ServerManager) AutostartAll() {
+	for _, inst := range m.List() {
+		if !inst.Autostart {
+			continue
+		}
+		if err := m.Start(inst.ID); err != nil {
+			log.Printf("autostart failed for instance %q: %v", inst.ID, err)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestRunBackupUnknownInstance(t *testing.T) {
+	m := NewServerManager(nil, "")
+	if err := m.RunBackup("missing"); err == nil {
+		t.Fatal("expected RunBackup to fail for an unknown instance")
+	}
+}
+
+func TestRestoreBackupRoutesThroughManagerLock(t *testing.T) {
+	m := NewServerManager([]InstanceConfig{{ID: "test"}}, "")
+	inst, _ := m.Get("test")
+	inst.cmd = exec.Command("true")
+
+	if err := m.RestoreBackup("test", "test-whatever.tar.gz"); err == nil {
+		t.Fatal("expected RestoreBackup to refuse while the instance is running")
+	}
+}
+
+func TestUpdateConfigPersistsPatch(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "instances.json")
+	m := NewServerManager([]InstanceConfig{{ID: "test", Name: "old"}}, path)
+
+	newName := "new"
+	got, err := m.UpdateConfig("test", instanceConfigPatch{Name: &newName})
+	if err != nil {
+		t.Fatalf("UpdateConfig: %v", err)
+	}
+	if got.Name != "new" {
+		t.Errorf("Name = %q, want %q", got.Name, "new")
+	}
+
+	reloaded, err := LoadInstanceConfigs(path)
+	if err != nil {
+		t.Fatalf("LoadInstanceConfigs: %v", err)
+	}
+	if len(reloaded) != 1 || reloaded[0].Name != "new" {
+		t.Errorf("persisted config = %+v, want Name %q", reloaded, "new")
+	}
+}
+
+func TestUpdateConfigRefusesWhileRunning(t *testing.T) {
+	m := NewServerManager([]InstanceConfig{{ID: "test"}}, "")
+	inst, _ := m.Get("test")
+	inst.cmd = exec.Command("true")
+
+	newName := "new"
+	if _, err := m.UpdateConfig("test", instanceConfigPatch{Name: &newName}); err == nil {
+		t.Fatal("expected UpdateConfig to refuse while the instance is running")
+	}
+}
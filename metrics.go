@@ -0,0 +1,71 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Prometheus collectors for /metrics. Instances and handlers update these
+// directly as events happen (a command is sent, a log line arrives, a
+// client subscribes) rather than anything polling instance state on scrape.
+var (
+	serverUp = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "tmod_server_up",
+		Help: "Whether an instance's child process is currently running (1) or not (0).",
+	}, []string{"instance"})
+
+	playersOnline = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "tmod_players_online",
+		Help: "Number of players currently connected to an instance.",
+	}, []string{"instance"})
+
+	websocketClients = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "tmod_websocket_clients",
+		Help: "Number of WebSocket clients subscribed to an instance, across the event stream and console endpoints.",
+	}, []string{"instance"})
+
+	commandsSentTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "tmod_commands_sent_total",
+		Help: "Total number of console commands sent to an instance.",
+	}, []string{"command"})
+
+	startFailuresTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "tmod_start_failures_total",
+		Help: "Total number of failed attempts to start an instance.",
+	}, []string{"instance"})
+
+	logLinesTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "tmod_log_lines_total",
+		Help: "Total number of console log lines recorded, by inferred level.",
+	}, []string{"level"})
+)
+
+func init() {
+	prometheus.MustRegister(serverUp, playersOnline, websocketClients, commandsSentTotal, startFailuresTotal, logLinesTotal)
+}
+
+// commandLabel reduces a raw console command line to its first word so the
+// tmod_commands_sent_total label stays bounded regardless of arguments.
+func commandLabel(line string) string {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return "unknown"
+	}
+	return fields[0]
+}
+
+// logLevel classifies a raw console line for tmod_log_lines_total by simple
+// substring matching, since tModLoader's console output has no structured
+// level field to parse.
+func logLevel(line string) string {
+	lower := strings.ToLower(line)
+	switch {
+	case strings.Contains(lower, "error"):
+		return "error"
+	case strings.Contains(lower, "warn"):
+		return "warn"
+	default:
+		return "info"
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"log"
+
+	"github.com/robfig/cron/v3"
+)
+
+// BackupScheduler runs each instance's configured backup cron spec,
+// triggering ServerManager.RunBackup on schedule.
+type BackupScheduler struct {
+	cron    *cron.Cron
+	manager *ServerManager
+}
+
+// NewBackupScheduler builds a scheduler with no jobs registered yet; call
+// Schedule for each instance and then Start. Backups run through manager so
+// they're serialized against Start/Stop/Restart/UpdateConfig on the same
+// instance.
+func NewBackupScheduler(manager *ServerManager) *BackupScheduler {
+	return &BackupScheduler{cron: cron.New(), manager: manager}
+}
+
+// Schedule registers inst's BackupCron spec, if it has one. It is a no-op
+// for instances with no schedule configured.
+func (s *BackupScheduler) Schedule(inst *Instance) error {
+	if inst.BackupCron == "" {
+		return nil
+	}
+	id := inst.ID
+	_, err := s.cron.AddFunc(inst.BackupCron, func() {
+		if err := s.manager.RunBackup(id); err != nil {
+			log.Printf("instance %q: scheduled backup failed: %v", id, err)
+		}
+	})
+	return err
+}
+
+// Start begins running scheduled backups in the background.
+func (s *BackupScheduler) Start() {
+	s.cron.Start()
+}
+
+// Stop waits for any in-flight scheduled backup to finish and stops
+// scheduling new ones.
+func (s *BackupScheduler) Stop() {
+	<-s.cron.Stop().Done()
+}